@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AMIRunner is a Runner that talks to Asterisk over the Manager Interface
+// (AMI) instead of shelling out to the CLI. It keeps a single persistent,
+// authenticated TCP connection open and serializes actions over it, so it
+// can be used from a box that only has network access to the PBX.
+type AMIRunner struct {
+	Address  string
+	Username string
+	Secret   string
+	Timeout  time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewAMIRunner returns an AMIRunner that will log in to the AMI listener at
+// address (host:port) with the given username/secret on first use.
+func NewAMIRunner(address, username, secret string) *AMIRunner {
+	return &AMIRunner{
+		Address:  address,
+		Username: username,
+		Secret:   secret,
+		Timeout:  5 * time.Second,
+	}
+}
+
+// amiMessage is one "Action:"/"Response:"/"Event:" block, as a set of
+// colon-separated key/value header lines.
+type amiMessage map[string]string
+
+func (c *AMIRunner) ensureConnected() error {
+	if c.conn != nil {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", c.Address, c.Timeout)
+	if err != nil {
+		return fmt.Errorf("ami: dial %s: %w", c.Address, err)
+	}
+
+	c.conn = conn
+	c.r = bufio.NewReader(conn)
+
+	// Banner, e.g. "Asterisk Call Manager/x.y.z"
+	if _, err := c.readLine(); err != nil {
+		c.closeLocked()
+		return fmt.Errorf("ami: reading banner: %w", err)
+	}
+
+	if err := c.login(); err != nil {
+		c.closeLocked()
+		return err
+	}
+
+	return nil
+}
+
+// closeLocked closes and discards the current connection, if any. Callers
+// must hold c.mu.
+func (c *AMIRunner) closeLocked() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn = nil
+	c.r = nil
+}
+
+// Close closes the underlying AMI connection, if one is open. It satisfies
+// the Runner interface so short-lived runners (e.g. MultiTargetHandler's
+// per-probe runners) can be torn down once a scrape is done with them.
+func (c *AMIRunner) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+	c.r = nil
+	return err
+}
+
+// readLine reads a single line with a read deadline derived from
+// c.Timeout, and drops the connection on any I/O error so the next call
+// reconnects instead of reusing a dead socket.
+func (c *AMIRunner) readLine() (string, error) {
+	if err := c.conn.SetReadDeadline(time.Now().Add(c.Timeout)); err != nil {
+		c.closeLocked()
+		return "", fmt.Errorf("ami: set read deadline: %w", err)
+	}
+
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.closeLocked()
+		return "", fmt.Errorf("ami: read: %w", err)
+	}
+
+	return line, nil
+}
+
+func (c *AMIRunner) login() error {
+	resp, err := c.sendAction(map[string]string{
+		"Action":   "Login",
+		"Username": c.Username,
+		"Secret":   c.Secret,
+		// Suppress unsolicited events so actionEvents only ever has to read
+		// the Response/Event blocks belonging to the action it sent, not a
+		// live feed of Hangup/PeerStatus/etc. interleaved with them.
+		"Events": "off",
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp["Response"] != "Success" {
+		return fmt.Errorf("ami: login failed: %s", resp["Message"])
+	}
+
+	return nil
+}
+
+// sendAction writes an action and reads the immediate Response: block that
+// follows it (not any of the Event: blocks it may trigger).
+func (c *AMIRunner) sendAction(fields map[string]string) (amiMessage, error) {
+	var b strings.Builder
+	for k, v := range fields {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, v)
+	}
+	b.WriteString("\r\n")
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.Timeout)); err != nil {
+		c.closeLocked()
+		return nil, fmt.Errorf("ami: set write deadline: %w", err)
+	}
+
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		c.closeLocked()
+		return nil, fmt.Errorf("ami: write action: %w", err)
+	}
+
+	return c.readMessage()
+}
+
+// actionEvents runs an action and collects every Event: block up to the
+// terminating "EventList: Complete" event.
+func (c *AMIRunner) actionEvents(action string) ([]amiMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	if _, err := c.sendAction(map[string]string{"Action": action}); err != nil {
+		return nil, err
+	}
+
+	var events []amiMessage
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, msg)
+
+		if strings.HasSuffix(msg["EventList"], "Complete") {
+			break
+		}
+	}
+
+	return events, nil
+}
+
+func (c *AMIRunner) readMessage() (amiMessage, error) {
+	msg := amiMessage{}
+
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		msg[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return msg, nil
+}
+
+// peerStatusWord returns the leading word of an AMI peer Status value
+// ("OK (5 ms)" -> "OK"), or "" if status is empty.
+func peerStatusWord(status string) string {
+	fields := strings.Fields(status)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// PeersInfo runs the SIPpeers AMI action.
+func (c *AMIRunner) PeersInfo() *PeersInfo {
+	info := &PeersInfo{}
+
+	events, err := c.actionEvents("SIPpeers")
+	if err != nil {
+		return info
+	}
+
+	for _, ev := range events {
+		if ev["Event"] != "PeerEntry" {
+			continue
+		}
+
+		name := ev["ObjectName"]
+		// AMI reports e.g. "OK (5 ms)"/"LAGGED (900 ms)"; strip the RTT
+		// parenthetical so Peer.Status matches the bare word CmdRunner's
+		// peerLineRegexp produces, keeping peer_status{peer_status=...}
+		// consistent across transports.
+		status := peerStatusWord(ev["Status"])
+		info.IndividualPeers = append(info.IndividualPeers, Peer{Name: name, Status: status})
+
+		switch {
+		case strings.HasPrefix(status, "OK"):
+			info.MonitoredOnline++
+			info.PeersStatusQualified++
+		case strings.HasPrefix(status, "LAGGED"):
+			info.MonitoredOnline++
+			info.PeersStatusQualified++
+		case strings.HasPrefix(status, "UNREACHABLE"):
+			info.MonitoredOffline++
+		case strings.HasPrefix(status, "Unmonitored"):
+			info.UnmonitoredOnline++
+		default:
+			info.PeersStatusUnknown++
+		}
+	}
+
+	return info
+}
+
+// SipChannelsInfo runs the CoreShowChannels AMI action and counts the SIP
+// channels among the results.
+func (c *AMIRunner) SipChannelsInfo() *SipChannelsInfo {
+	info := &SipChannelsInfo{}
+
+	events, err := c.actionEvents("CoreShowChannels")
+	if err != nil {
+		return info
+	}
+
+	for _, ev := range events {
+		if ev["Event"] != "CoreShowChannel" {
+			continue
+		}
+		if strings.HasPrefix(ev["Channel"], "SIP/") {
+			info.ActiveSipDialogs++
+			info.ActiveSipChannels++
+		}
+	}
+
+	return info
+}
+
+// UsersInfo is not exposed over AMI; chan_sip users are a dialplan/config
+// concept with no corresponding manager action, so this always returns a
+// zero-value result.
+func (c *AMIRunner) UsersInfo() *UsersInfo {
+	return &UsersInfo{}
+}
+
+// RegistriesInfo runs the SIPshowregistry AMI action.
+func (c *AMIRunner) RegistriesInfo() *RegistriesInfo {
+	info := &RegistriesInfo{}
+
+	events, err := c.actionEvents("SIPshowregistry")
+	if err != nil {
+		return info
+	}
+
+	for _, ev := range events {
+		if ev["Event"] != "RegistryEntry" {
+			continue
+		}
+
+		username := ev["Username"]
+		state := ev["State"]
+
+		info.TotalRegistrations++
+		info.IndividualRegistrations = append(info.IndividualRegistrations, Registration{Username: username, State: state})
+
+		if strings.EqualFold(state, "Registered") {
+			info.OnlineRegistrations++
+		} else {
+			info.OfflineRegistrations++
+		}
+	}
+
+	return info
+}
+
+// PJSIPEndpointsInfo runs the PJSIPShowEndpoints AMI action.
+func (c *AMIRunner) PJSIPEndpointsInfo() (*PJSIPEndpointsInfo, error) {
+	info := &PJSIPEndpointsInfo{}
+
+	events, err := c.actionEvents("PJSIPShowEndpoints")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ev := range events {
+		if ev["Event"] != "EndpointList" {
+			continue
+		}
+
+		info.Endpoints = append(info.Endpoints, PJSIPEndpoint{
+			Name:   ev["ObjectName"],
+			AOR:    ev["Aor"],
+			Status: ev["DeviceState"],
+		})
+	}
+
+	return info, nil
+}
+
+// PJSIPContactsInfo and PJSIPRegistrationsInfo have no direct AMI equivalent
+// of the CLI commands they mirror, so the AMI transport reports them as
+// empty rather than guessing at a mapping.
+func (c *AMIRunner) PJSIPContactsInfo() (*PJSIPContactsInfo, error) {
+	return &PJSIPContactsInfo{}, nil
+}
+
+func (c *AMIRunner) PJSIPRegistrationsInfo() (*PJSIPRegistrationsInfo, error) {
+	return &PJSIPRegistrationsInfo{}, nil
+}
+
+// PJSIPChannelStatsInfo runs the same CoreShowChannels AMI action as
+// SipChannelsInfo and counts the PJSIP/... channels among the results,
+// rather than the SIP/... ones.
+func (c *AMIRunner) PJSIPChannelStatsInfo() (*PJSIPChannelStatsInfo, error) {
+	info := &PJSIPChannelStatsInfo{}
+
+	events, err := c.actionEvents("CoreShowChannels")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ev := range events {
+		if ev["Event"] != "CoreShowChannel" {
+			continue
+		}
+		if strings.HasPrefix(ev["Channel"], "PJSIP/") {
+			info.ActiveChannels++
+		}
+	}
+
+	return info, nil
+}
+
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+// newAMIPipe returns an AMIRunner wired directly to the client end of a
+// net.Pipe, letting readMessage/readLine be exercised without dialing a real
+// socket or PBX.
+func newAMIPipe() (*AMIRunner, net.Conn) {
+	client, server := net.Pipe()
+	c := &AMIRunner{conn: client, r: bufio.NewReader(client), Timeout: time.Second}
+	return c, server
+}
+
+func TestAMIRunner_ReadMessage(t *testing.T) {
+	c, server := newAMIPipe()
+	defer server.Close()
+
+	go func() {
+		server.Write([]byte("Response: Success\r\nMessage: Authenticated\r\n\r\n"))
+	}()
+
+	msg, err := c.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage: %v", err)
+	}
+
+	if msg["Response"] != "Success" || msg["Message"] != "Authenticated" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+func TestAMIRunner_ReadMessage_IgnoresMalformedLines(t *testing.T) {
+	c, server := newAMIPipe()
+	defer server.Close()
+
+	go func() {
+		server.Write([]byte("Response: Success\r\nnot a header line\r\nMessage: ok\r\n\r\n"))
+	}()
+
+	msg, err := c.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage: %v", err)
+	}
+
+	if len(msg) != 2 || msg["Response"] != "Success" || msg["Message"] != "ok" {
+		t.Errorf("expected malformed line to be skipped, got %+v", msg)
+	}
+}
+
+func TestAMIRunner_ReadLine_DropsConnectionOnError(t *testing.T) {
+	c, server := newAMIPipe()
+
+	server.Close() // closes the pipe out from under the reader
+
+	if _, err := c.readLine(); err == nil {
+		t.Fatal("expected an error reading from a closed connection")
+	}
+
+	if c.conn != nil || c.r != nil {
+		t.Error("expected readLine to drop the connection on I/O error so the next call reconnects")
+	}
+}
+
+func TestAMIRunner_ActionEvents_StopsAtEventListComplete(t *testing.T) {
+	c, server := newAMIPipe()
+	defer server.Close()
+
+	go func() {
+		// Drain the Action request before replying.
+		r := bufio.NewReader(server)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil || line == "\r\n" {
+				break
+			}
+		}
+
+		server.Write([]byte("Response: Success\r\n\r\n"))
+		server.Write([]byte("Event: PeerEntry\r\nObjectName: 1000\r\nStatus: OK (5 ms)\r\n\r\n"))
+		server.Write([]byte("Event: PeerEntry\r\nObjectName: 1001\r\nStatus: UNREACHABLE\r\n\r\n"))
+		server.Write([]byte("Event: PeerlistComplete\r\nEventList: Complete\r\n\r\n"))
+	}()
+
+	events, err := c.actionEvents("SIPpeers")
+	if err != nil {
+		t.Fatalf("actionEvents: %v", err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events (2 PeerEntry + terminator), got %d: %+v", len(events), events)
+	}
+	if events[0]["ObjectName"] != "1000" || events[1]["ObjectName"] != "1001" {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
+
+func TestPeerStatusWord(t *testing.T) {
+	cases := map[string]string{
+		"OK (5 ms)":        "OK",
+		"LAGGED (900 ms)":  "LAGGED",
+		"UNREACHABLE":      "UNREACHABLE",
+		"Unmonitored":      "Unmonitored",
+		"":                 "",
+		"  UNKNOWN  extra": "UNKNOWN",
+	}
+
+	for in, want := range cases {
+		if got := peerStatusWord(in); got != want {
+			t.Errorf("peerStatusWord(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// fakeAMIServer is a minimal loopback AMI listener that accepts connections
+// until closed and, on each one, sends the banner, handles the Login
+// handshake, and then hands off the connection to onAction for each
+// subsequent Action block.
+// It exercises AMIRunner's real dial/reconnect path (ensureConnected),
+// rather than a net.Pipe stood in for it directly.
+type fakeAMIServer struct {
+	ln net.Listener
+}
+
+func newFakeAMIServer(t *testing.T, onAction func(conn net.Conn, action string)) *fakeAMIServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	s := &fakeAMIServer{ln: ln}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				conn.Write([]byte("Asterisk Call Manager/2.10.6\r\n"))
+
+				r := bufio.NewReader(conn)
+				for {
+					action, ok := readActionName(r)
+					if !ok {
+						return
+					}
+
+					if action == "Login" {
+						conn.Write([]byte("Response: Success\r\nMessage: Authentication accepted\r\n\r\n"))
+						continue
+					}
+
+					onAction(conn, action)
+				}
+			}(conn)
+		}
+	}()
+
+	return s
+}
+
+// readActionName reads one AMI message off r and returns its "Action"
+// header, or ok=false on EOF/error.
+func readActionName(r *bufio.Reader) (action string, ok bool) {
+	headers := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", false
+		}
+
+		line = line[:len(line)-1]
+		if len(line) > 0 && line[len(line)-1] == '\r' {
+			line = line[:len(line)-1]
+		}
+		if line == "" {
+			return headers["Action"], true
+		}
+
+		for i := 0; i < len(line); i++ {
+			if line[i] == ':' {
+				headers[line[:i]] = line[i+2:]
+				break
+			}
+		}
+	}
+}
+
+func (s *fakeAMIServer) Close() { s.ln.Close() }
+
+func TestAMIRunner_PJSIPChannelStatsInfo_CountsOnlyPJSIPChannels(t *testing.T) {
+	server := newFakeAMIServer(t, func(conn net.Conn, action string) {
+		if action != "CoreShowChannels" {
+			return
+		}
+		conn.Write([]byte("Response: Success\r\n\r\n"))
+		conn.Write([]byte("Event: CoreShowChannel\r\nChannel: SIP/1000-00000001\r\n\r\n"))
+		conn.Write([]byte("Event: CoreShowChannel\r\nChannel: PJSIP/2000-00000002\r\n\r\n"))
+		conn.Write([]byte("Event: CoreShowChannel\r\nChannel: PJSIP/2001-00000003\r\n\r\n"))
+		conn.Write([]byte("Event: CoreShowChannelsComplete\r\nEventList: Complete\r\n\r\n"))
+	})
+	defer server.Close()
+
+	c := NewAMIRunner(server.ln.Addr().String(), "admin", "secret")
+	c.Timeout = time.Second
+
+	info, err := c.PJSIPChannelStatsInfo()
+	if err != nil {
+		t.Fatalf("PJSIPChannelStatsInfo: %v", err)
+	}
+	if info.ActiveChannels != 2 {
+		t.Errorf("expected 2 PJSIP channels, got %d", info.ActiveChannels)
+	}
+}
+
+func TestAMIRunner_LoginAndReconnectAfterIOError(t *testing.T) {
+	server := newFakeAMIServer(t, func(conn net.Conn, action string) {
+		if action == "CoreShowChannels" {
+			conn.Write([]byte("Response: Success\r\n\r\n"))
+			conn.Write([]byte("Event: CoreShowChannelsComplete\r\nEventList: Complete\r\n\r\n"))
+		}
+	})
+	defer server.Close()
+
+	c := NewAMIRunner(server.ln.Addr().String(), "admin", "secret")
+	c.Timeout = time.Second
+
+	if info := c.SipChannelsInfo(); info.ActiveSipDialogs != 0 {
+		t.Fatalf("unexpected channels info: %+v", info)
+	}
+
+	if c.conn == nil {
+		t.Fatal("expected the connection to be kept open across calls")
+	}
+
+	// Simulate the peer dropping the TCP connection underneath the runner.
+	// The call that observes the dead socket fails and drops it...
+	c.conn.Close()
+	c.SipChannelsInfo()
+	if c.conn != nil {
+		t.Fatal("expected the dead connection to be dropped after an I/O error")
+	}
+
+	// ...and the next call dials a fresh connection and succeeds again.
+	if info := c.SipChannelsInfo(); info.ActiveSipDialogs != 0 {
+		t.Fatalf("unexpected channels info after reconnect: %+v", info)
+	}
+	if c.conn == nil {
+		t.Error("expected the runner to have a fresh connection after reconnecting")
+	}
+}
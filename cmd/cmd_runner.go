@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Runner is the set of Asterisk queries every collector backend must
+// support, regardless of how it actually talks to Asterisk. CmdRunner
+// satisfies it by shelling out to the CLI; AMIRunner satisfies it over a
+// persistent AMI connection.
+type Runner interface {
+	PeersInfo() *PeersInfo
+	SipChannelsInfo() *SipChannelsInfo
+	UsersInfo() *UsersInfo
+	RegistriesInfo() *RegistriesInfo
+
+	// Close releases any resources (e.g. a persistent AMI connection) held
+	// by the runner. Callers that build a short-lived runner, such as
+	// MultiTargetHandler's per-probe runners, must call it once done.
+	Close() error
+}
+
+// CmdRunner gathers Asterisk state by shelling out to the `asterisk` CLI
+// binary (`asterisk -rx "<command>"`) and parsing its plain-text output.
+type CmdRunner struct {
+	BinPath string
+}
+
+// NewCmdRunner returns a CmdRunner that invokes the Asterisk CLI at binPath.
+func NewCmdRunner(binPath string) *CmdRunner {
+	return &CmdRunner{BinPath: binPath}
+}
+
+// Close is a no-op: CmdRunner shells out independently on every call and
+// holds no persistent connection to release.
+func (c *CmdRunner) Close() error {
+	return nil
+}
+
+func (c *CmdRunner) exec(command string) (string, error) {
+	out, err := exec.Command(c.BinPath, "-rx", command).CombinedOutput()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// Peer is the status of a single SIP peer, as reported by `sip show peers`.
+type Peer struct {
+	Name   string
+	Status string
+}
+
+// PeersInfo is the parsed output of `sip show peers`.
+type PeersInfo struct {
+	MonitoredOnline      int
+	MonitoredOffline     int
+	UnmonitoredOnline    int
+	UnmonitoredOffline   int
+	PeersStatusUnknown   int
+	PeersStatusQualified int
+	IndividualPeers      []Peer
+}
+
+var peerLineRegexp = regexp.MustCompile(`^(\S+)/\S*\s+.*\s+(OK|UNKNOWN|UNREACHABLE|LAGGED|Unmonitored)\b`)
+
+// PeersInfo runs `sip show peers` and parses the result.
+func (c *CmdRunner) PeersInfo() *PeersInfo {
+	info := &PeersInfo{}
+
+	out, err := c.exec("sip show peers")
+	if err != nil {
+		return info
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		m := peerLineRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		name, status := m[1], m[2]
+		info.IndividualPeers = append(info.IndividualPeers, Peer{Name: name, Status: status})
+
+		switch status {
+		case "OK":
+			info.MonitoredOnline++
+			info.PeersStatusQualified++
+		case "LAGGED":
+			info.MonitoredOnline++
+			info.PeersStatusQualified++
+		case "UNREACHABLE":
+			info.MonitoredOffline++
+		case "Unmonitored":
+			info.UnmonitoredOnline++
+		case "UNKNOWN":
+			info.PeersStatusUnknown++
+		}
+	}
+
+	return info
+}
+
+// SipChannelsInfo is the parsed output of `sip show channels`.
+type SipChannelsInfo struct {
+	ActiveSipDialogs       int
+	ActiveSipSubscriptions int
+	ActiveSipChannels      int
+}
+
+var activeDialogsRegexp = regexp.MustCompile(`(\d+)\s+active SIP dialog`)
+
+// SipChannelsInfo runs `sip show channels` and parses the summary line.
+func (c *CmdRunner) SipChannelsInfo() *SipChannelsInfo {
+	info := &SipChannelsInfo{}
+
+	out, err := c.exec("sip show channels")
+	if err != nil {
+		return info
+	}
+
+	if m := activeDialogsRegexp.FindStringSubmatch(out); m != nil {
+		info.ActiveSipDialogs, _ = strconv.Atoi(m[1])
+		info.ActiveSipChannels = info.ActiveSipDialogs
+	}
+
+	return info
+}
+
+// UsersInfo is the parsed output of `sip show users`.
+type UsersInfo struct {
+	Users int
+}
+
+// UsersInfo runs `sip show users` and counts the listed users.
+func (c *CmdRunner) UsersInfo() *UsersInfo {
+	info := &UsersInfo{}
+
+	out, err := c.exec("sip show users")
+	if err != nil {
+		return info
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) > 1 {
+		info.Users = len(lines) - 1
+	}
+
+	return info
+}
+
+// Registration is the status of a single SIP registration, as reported by
+// `sip show registry`.
+type Registration struct {
+	Username string
+	State    string
+}
+
+// RegistriesInfo is the parsed output of `sip show registry`.
+type RegistriesInfo struct {
+	TotalRegistrations      int
+	OnlineRegistrations     int
+	OfflineRegistrations    int
+	IndividualRegistrations []Registration
+}
+
+var registryLineRegexp = regexp.MustCompile(`^\S+\s+\S*\s*(\S+)@\S+\s+\S+\s+(\S+)\s*$`)
+
+// RegistriesInfo runs `sip show registry` and parses the result.
+func (c *CmdRunner) RegistriesInfo() *RegistriesInfo {
+	info := &RegistriesInfo{}
+
+	out, err := c.exec("sip show registry")
+	if err != nil {
+		return info
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		m := registryLineRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		username, state := m[1], m[2]
+		info.TotalRegistrations++
+		info.IndividualRegistrations = append(info.IndividualRegistrations, Registration{Username: username, State: state})
+
+		if strings.EqualFold(state, "Registered") {
+			info.OnlineRegistrations++
+		} else {
+			info.OfflineRegistrations++
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DetailedPeerRunner is satisfied by backends that can fetch per-peer detail
+// beyond the summary `sip show peers` line. It's a separate, optional
+// interface rather than part of Runner because fetching it means one extra
+// round-trip per peer, which callers opt into explicitly.
+type DetailedPeerRunner interface {
+	PeerDetail(name string) (*PeerDetail, error)
+}
+
+// PeerDetail is the parsed output of `sip show peer <name>`.
+type PeerDetail struct {
+	QualifyRTTMillis float64
+	// LastQualifiedAt is the zero Time unless a "Status: OK (N ms)" line was
+	// actually parsed out of this fetch, i.e. it reflects the last
+	// successful qualify, not merely the last time the cache was refreshed.
+	LastQualifiedAt time.Time
+	Contact         string
+	Useragent       string
+}
+
+var (
+	peerStatusRTTRegexp = regexp.MustCompile(`Status\s*:\s*OK \((\d+) ms\)`)
+	peerContactRegexp   = regexp.MustCompile(`Reg\. Contact\s*:\s*(.+)`)
+	peerUseragentRegexp = regexp.MustCompile(`Useragent\s*:\s*(.+)`)
+)
+
+// PeerDetail runs `sip show peer <name>` and parses the result.
+func (c *CmdRunner) PeerDetail(name string) (*PeerDetail, error) {
+	out, err := c.exec(fmt.Sprintf("sip show peer %s", name))
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePeerDetail(out), nil
+}
+
+// parsePeerDetail is split out from PeerDetail so it can be exercised
+// directly with fixture output, without shelling out.
+func parsePeerDetail(out string) *PeerDetail {
+	detail := &PeerDetail{}
+
+	if m := peerStatusRTTRegexp.FindStringSubmatch(out); m != nil {
+		detail.QualifyRTTMillis, _ = strconv.ParseFloat(m[1], 64)
+		detail.LastQualifiedAt = time.Now()
+	}
+
+	if m := peerContactRegexp.FindStringSubmatch(out); m != nil {
+		detail.Contact = strings.TrimSpace(m[1])
+	}
+
+	if m := peerUseragentRegexp.FindStringSubmatch(out); m != nil {
+		detail.Useragent = strings.TrimSpace(m[1])
+	}
+
+	return detail
+}
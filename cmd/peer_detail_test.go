@@ -0,0 +1,51 @@
+package cmd
+
+import "testing"
+
+const samplePeerOutput = `
+ * Name       : 1000
+  Secret       : <Set>
+  Context      : default
+  Qualify Freq : 60000 ms
+  Status       : OK (23 ms)
+  Reg. Contact : sip:1000@192.168.1.50:5060
+  Useragent    : Zoiper rv2.10.14.5
+`
+
+func TestParsePeerDetail(t *testing.T) {
+	detail := parsePeerDetail(samplePeerOutput)
+
+	if detail.QualifyRTTMillis != 23 {
+		t.Errorf("expected QualifyRTTMillis 23, got %v", detail.QualifyRTTMillis)
+	}
+
+	if detail.Contact != "sip:1000@192.168.1.50:5060" {
+		t.Errorf("expected Contact %q, got %q", "sip:1000@192.168.1.50:5060", detail.Contact)
+	}
+
+	if detail.Useragent != "Zoiper rv2.10.14.5" {
+		t.Errorf("expected Useragent %q, got %q", "Zoiper rv2.10.14.5", detail.Useragent)
+	}
+
+	if detail.LastQualifiedAt.IsZero() {
+		t.Error("expected LastQualifiedAt to be set")
+	}
+}
+
+func TestParsePeerDetail_Unreachable(t *testing.T) {
+	out := `
+ * Name       : 1001
+  Status       : UNREACHABLE
+`
+	detail := parsePeerDetail(out)
+
+	if detail.QualifyRTTMillis != 0 {
+		t.Errorf("expected QualifyRTTMillis 0 for an unreachable peer, got %v", detail.QualifyRTTMillis)
+	}
+	if detail.Contact != "" {
+		t.Errorf("expected empty Contact for an unreachable peer, got %q", detail.Contact)
+	}
+	if !detail.LastQualifiedAt.IsZero() {
+		t.Errorf("expected zero LastQualifiedAt for an unreachable peer, got %v", detail.LastQualifiedAt)
+	}
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PJSIPRunner is the set of PJSIP queries a collector backend must support
+// to back pjsipCollector. Both CmdRunner and AMIRunner satisfy it.
+type PJSIPRunner interface {
+	PJSIPEndpointsInfo() (*PJSIPEndpointsInfo, error)
+	PJSIPContactsInfo() (*PJSIPContactsInfo, error)
+	PJSIPRegistrationsInfo() (*PJSIPRegistrationsInfo, error)
+	PJSIPChannelStatsInfo() (*PJSIPChannelStatsInfo, error)
+}
+
+// PJSIPEndpoint is the status of a single PJSIP endpoint, as reported by
+// `pjsip show endpoints`.
+type PJSIPEndpoint struct {
+	Name   string
+	AOR    string
+	Status string
+}
+
+// PJSIPEndpointsInfo is the parsed output of `pjsip show endpoints`.
+type PJSIPEndpointsInfo struct {
+	Endpoints []PJSIPEndpoint
+}
+
+// pjsip show endpoints prints one "Endpoint:" line per endpoint (name and
+// device state), followed on its own line by the "Aor:" it resolves to, e.g.:
+//
+//	Endpoint:  1000/1000                                            Not in use    0 of inf
+//	    Aor:  1000                                                    0
+var (
+	pjsipEndpointRegexp = regexp.MustCompile(`^\s*Endpoint:\s+(\S+?)(?:/\S+)?\s+(Not in use|In use|Unavailable|Unknown|Ringing|Ring\+Inuse|On Hold|Busy)\b`)
+	pjsipAorRegexp      = regexp.MustCompile(`^\s*Aor:\s+(\S+)`)
+)
+
+// PJSIPEndpointsInfo runs `pjsip show endpoints` and parses the result.
+func (c *CmdRunner) PJSIPEndpointsInfo() (*PJSIPEndpointsInfo, error) {
+	out, err := c.exec("pjsip show endpoints")
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePJSIPEndpoints(out), nil
+}
+
+// parsePJSIPEndpoints is split out from PJSIPEndpointsInfo so it can be
+// exercised directly with fixture output, without shelling out.
+func parsePJSIPEndpoints(out string) *PJSIPEndpointsInfo {
+	info := &PJSIPEndpointsInfo{}
+
+	var pending *PJSIPEndpoint
+	for _, line := range strings.Split(out, "\n") {
+		if m := pjsipEndpointRegexp.FindStringSubmatch(line); m != nil {
+			if pending != nil {
+				info.Endpoints = append(info.Endpoints, *pending)
+			}
+			pending = &PJSIPEndpoint{Name: m[1], Status: m[2]}
+			continue
+		}
+
+		if pending != nil {
+			if m := pjsipAorRegexp.FindStringSubmatch(line); m != nil {
+				pending.AOR = m[1]
+				info.Endpoints = append(info.Endpoints, *pending)
+				pending = nil
+			}
+		}
+	}
+	if pending != nil {
+		info.Endpoints = append(info.Endpoints, *pending)
+	}
+
+	return info
+}
+
+// PJSIPContact is the status of a single PJSIP contact, as reported by
+// `pjsip show contacts`. RttMs is kept as a plain field here (not baked
+// into Status) so callers can surface it as its own metric value instead
+// of a label.
+type PJSIPContact struct {
+	URI    string
+	Status string
+	RttMs  float64
+}
+
+// PJSIPContactsInfo is the parsed output of `pjsip show contacts`.
+type PJSIPContactsInfo struct {
+	Contacts []PJSIPContact
+}
+
+// pjsip show contacts prints one "Contact:" line per contact:
+//
+//	Contact:  1000/sip:1000@192.168.1.50:5060   9c1c2e3a2c  Avail        23.456
+//
+// i.e. <aor>/<uri>, a hash-id column, then status and RTT in milliseconds.
+var pjsipContactRegexp = regexp.MustCompile(`^\s*Contact:\s+(\S+)\s+\S+\s+(Avail|Unavail|Unknown)\s+([\d.]+)`)
+
+// PJSIPContactsInfo runs `pjsip show contacts` and parses the result.
+func (c *CmdRunner) PJSIPContactsInfo() (*PJSIPContactsInfo, error) {
+	out, err := c.exec("pjsip show contacts")
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePJSIPContacts(out), nil
+}
+
+// parsePJSIPContacts is split out from PJSIPContactsInfo so it can be
+// exercised directly with fixture output, without shelling out.
+func parsePJSIPContacts(out string) *PJSIPContactsInfo {
+	info := &PJSIPContactsInfo{}
+
+	for _, line := range strings.Split(out, "\n") {
+		m := pjsipContactRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		rtt, _ := strconv.ParseFloat(m[3], 64)
+		info.Contacts = append(info.Contacts, PJSIPContact{URI: m[1], Status: m[2], RttMs: rtt})
+	}
+
+	return info
+}
+
+// PJSIPRegistration is the status of a single outbound PJSIP registration,
+// as reported by `pjsip show registrations`.
+type PJSIPRegistration struct {
+	Name  string
+	State string
+}
+
+// PJSIPRegistrationsInfo is the parsed output of `pjsip show registrations`.
+type PJSIPRegistrationsInfo struct {
+	Registrations []PJSIPRegistration
+}
+
+// pjsip show registrations prints <name>/<server_uri>, an auth-id column,
+// then the registration status:
+//
+//	reg1/sip:user@sip.provider.com                reg1                Registered
+var pjsipRegistrationRegexp = regexp.MustCompile(`^\s*(\S+)/\S+\s+\S+\s+(Registered|Unregistered|Rejected|Auth Rejected|No Authentication)\s*$`)
+
+// PJSIPRegistrationsInfo runs `pjsip show registrations` and parses the result.
+func (c *CmdRunner) PJSIPRegistrationsInfo() (*PJSIPRegistrationsInfo, error) {
+	out, err := c.exec("pjsip show registrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePJSIPRegistrations(out), nil
+}
+
+// parsePJSIPRegistrations is split out from PJSIPRegistrationsInfo so it
+// can be exercised directly with fixture output, without shelling out.
+func parsePJSIPRegistrations(out string) *PJSIPRegistrationsInfo {
+	info := &PJSIPRegistrationsInfo{}
+
+	for _, line := range strings.Split(out, "\n") {
+		m := pjsipRegistrationRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		info.Registrations = append(info.Registrations, PJSIPRegistration{
+			Name:  m[1],
+			State: m[2],
+		})
+	}
+
+	return info
+}
+
+// PJSIPChannelStatsInfo is the parsed output of `pjsip show channelstats`.
+type PJSIPChannelStatsInfo struct {
+	ActiveChannels int
+}
+
+// pjsip show channelstats lists one PJSIP/... channel id per line.
+var pjsipChannelLineRegexp = regexp.MustCompile(`^\s*PJSIP/\S+`)
+
+// PJSIPChannelStatsInfo runs `pjsip show channelstats` and counts the
+// listed channels.
+func (c *CmdRunner) PJSIPChannelStatsInfo() (*PJSIPChannelStatsInfo, error) {
+	out, err := c.exec("pjsip show channelstats")
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePJSIPChannelStats(out), nil
+}
+
+// parsePJSIPChannelStats is split out from PJSIPChannelStatsInfo so it can
+// be exercised directly with fixture output, without shelling out.
+func parsePJSIPChannelStats(out string) *PJSIPChannelStatsInfo {
+	info := &PJSIPChannelStatsInfo{}
+
+	for _, line := range strings.Split(out, "\n") {
+		if pjsipChannelLineRegexp.MatchString(line) {
+			info.ActiveChannels++
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import "testing"
+
+const samplePjsipEndpointsOutput = `
+ Endpoint:  1000/1000                                            Not in use    0 of inf
+   InAuth:  1000-auth/1000
+     Aor:  1000                                                    1
+   Contact:  1000/sip:1000@192.168.1.50:5060                     9c1c2e3a2c  Avail        23.456
+
+ Endpoint:  2000/2000                                             Unavailable   0 of inf
+     Aor:  2000                                                    0
+
+Objects found: 2
+`
+
+func TestParsePJSIPEndpoints(t *testing.T) {
+	info := parsePJSIPEndpoints(samplePjsipEndpointsOutput)
+
+	if len(info.Endpoints) != 2 {
+		t.Fatalf("expected 2 endpoints, got %d", len(info.Endpoints))
+	}
+
+	if got := info.Endpoints[0]; got.Name != "1000" || got.AOR != "1000" || got.Status != "Not in use" {
+		t.Errorf("unexpected first endpoint: %+v", got)
+	}
+
+	if got := info.Endpoints[1]; got.Name != "2000" || got.AOR != "2000" || got.Status != "Unavailable" {
+		t.Errorf("unexpected second endpoint: %+v", got)
+	}
+}
+
+const samplePjsipContactsOutput = `
+ Contact:  1000/sip:1000@192.168.1.50:5060                     9c1c2e3a2c  Avail        23.456
+ Contact:  2000/sip:2000@192.168.1.51:5060                     7a2b1f0d9e  Unavail      0.000
+
+Objects found: 2
+`
+
+func TestParsePJSIPContacts(t *testing.T) {
+	info := parsePJSIPContacts(samplePjsipContactsOutput)
+
+	if len(info.Contacts) != 2 {
+		t.Fatalf("expected 2 contacts, got %d", len(info.Contacts))
+	}
+
+	if got := info.Contacts[0]; got.URI != "1000/sip:1000@192.168.1.50:5060" || got.Status != "Avail" || got.RttMs != 23.456 {
+		t.Errorf("unexpected first contact: %+v", got)
+	}
+
+	if got := info.Contacts[1]; got.URI != "2000/sip:2000@192.168.1.51:5060" || got.Status != "Unavail" {
+		t.Errorf("unexpected second contact: %+v", got)
+	}
+}
+
+const samplePjsipRegistrationsOutput = `
+ reg1/sip:user1@sip.provider.com                reg1                Registered
+ reg2/sip:user2@sip.provider.com                reg2                Rejected
+
+Objects found: 2
+`
+
+func TestParsePJSIPRegistrations(t *testing.T) {
+	info := parsePJSIPRegistrations(samplePjsipRegistrationsOutput)
+
+	if len(info.Registrations) != 2 {
+		t.Fatalf("expected 2 registrations, got %d", len(info.Registrations))
+	}
+
+	if got := info.Registrations[0]; got.Name != "reg1" || got.State != "Registered" {
+		t.Errorf("unexpected first registration: %+v", got)
+	}
+
+	if got := info.Registrations[1]; got.Name != "reg2" || got.State != "Rejected" {
+		t.Errorf("unexpected second registration: %+v", got)
+	}
+}
+
+const samplePjsipChannelStatsOutput = `
+<ChannelId...............................>  <AudioCodec>  <AudioIP.......>  <RTT(ms)..>
+PJSIP/1000-00000001                         opus          192.168.1.50:60000  23.456
+PJSIP/2000-00000002                         ulaw          192.168.1.51:60000  11.000
+
+Objects found: 2
+`
+
+func TestParsePJSIPChannelStats(t *testing.T) {
+	info := parsePJSIPChannelStats(samplePjsipChannelStatsOutput)
+
+	if info.ActiveChannels != 2 {
+		t.Errorf("expected 2 active channels, got %d", info.ActiveChannels)
+	}
+}
@@ -0,0 +1,42 @@
+package collector
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Collector is the interface implemented by all per-subsystem collectors
+// (sipCollector, pjsipCollector, ...) so they can be registered and run
+// uniformly by the exporter.
+type Collector interface {
+	// Name returns the short name of the collector, used in log messages
+	// and in the collector_success/collector_duration_seconds labels.
+	Name() string
+	Describe(ch chan<- *prometheus.Desc)
+	Collect(ch chan<- prometheus.Metric)
+}
+
+// errorCollector is a degenerate Collector that reports collector_error=1
+// and nothing else, for a CollectorFactory that can't build the real
+// collector against the runner it was given (e.g. a probed target whose
+// runner doesn't implement an optional interface the collector needs).
+type errorCollector struct {
+	name           string
+	collectorError *prometheus.Desc
+}
+
+// NewErrorCollector returns a Collector that only ever reports
+// collector_error=1 under name, for use where a CollectorFactory can't
+// build the real collector against the runner it was given.
+func NewErrorCollector(name string, collectorError *prometheus.Desc) Collector {
+	return &errorCollector{name: name, collectorError: collectorError}
+}
+
+func (c *errorCollector) Name() string {
+	return c.name
+}
+
+func (c *errorCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.collectorError
+}
+
+func (c *errorCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.collectorError, prometheus.GaugeValue, 1, c.name)
+}
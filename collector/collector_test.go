@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestErrorCollector(t *testing.T) {
+	collectorError := prometheus.NewDesc("asterisk_collector_error", "", []string{"collector"}, nil)
+	c := NewErrorCollector("pjsip", collectorError)
+
+	if got := c.Name(); got != "pjsip" {
+		t.Errorf("expected Name() %q, got %q", "pjsip", got)
+	}
+
+	ch := make(chan prometheus.Metric, 1)
+	c.Collect(ch)
+	close(ch)
+
+	var metrics []prometheus.Metric
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+
+	if len(metrics) != 1 {
+		t.Fatalf("expected exactly one metric, got %d", len(metrics))
+	}
+
+	var pb dto.Metric
+	if err := metrics[0].Write(&pb); err != nil {
+		t.Fatalf("writing metric: %v", err)
+	}
+
+	if pb.GetGauge().GetValue() != 1 {
+		t.Errorf("expected collector_error=1, got %v", pb.GetGauge().GetValue())
+	}
+	if got := pb.GetLabel()[0].GetValue(); got != "pjsip" {
+		t.Errorf("expected collector label %q, got %q", "pjsip", got)
+	}
+}
@@ -0,0 +1,222 @@
+package collector
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/robinmarechal/asterisk_exporter/cmd"
+)
+
+// RunnerFactory builds the cmd.Runner used to scrape a given target
+// (host:port), e.g. CLI-over-SSH or AMI.
+type RunnerFactory func(target string) cmd.Runner
+
+// CollectorFactory builds one of the exporter's collectors (sipCollector,
+// pjsipCollector, ...) against the given runner.
+type CollectorFactory func(runner cmd.Runner) Collector
+
+// MultiTargetHandler serves GET /probe?target=host:port, the
+// blackbox-exporter-style pattern for monitoring many Asterisk instances
+// from a single exporter process. Each request builds a runner for the
+// requested target and runs every registered collector concurrently
+// against it, similar to mikrotik-exporter's deviceCollector.Collect.
+type MultiTargetHandler struct {
+	namespace      string
+	logger         log.Logger
+	newRunner      RunnerFactory
+	collectors     []CollectorFactory
+	collectorError *prometheus.Desc
+
+	scrapeDuration *prometheus.Desc
+	scrapeSuccess  *prometheus.Desc
+
+	mu    sync.Mutex
+	calls map[string]*probeCall
+}
+
+// probeCall coalesces overlapping /probe requests for the same target into
+// a single backend scrape, so a slow Prometheus retry storm doesn't pile up
+// concurrent scrapes of the same PBX.
+type probeCall struct {
+	wg      sync.WaitGroup
+	metrics []prometheus.Metric
+}
+
+// NewMultiTargetHandler returns a handler that scrapes newRunner(target)
+// with every given collector factory on each /probe request. collectorError
+// must be the same Desc instance passed to the collector factories, so the
+// handler can recognize each collector's own collector_error sample and
+// reflect it into collector_success.
+func NewMultiTargetHandler(namespace string, logger log.Logger, newRunner RunnerFactory, collectorError *prometheus.Desc, collectors ...CollectorFactory) *MultiTargetHandler {
+	return &MultiTargetHandler{
+		namespace:      namespace,
+		logger:         logger,
+		newRunner:      newRunner,
+		collectors:     collectors,
+		collectorError: collectorError,
+		scrapeDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "scrape", "collector_duration_seconds"),
+			"Duration of an individual collector's scrape of a probed target.",
+			[]string{"target", "collector"}, nil,
+		),
+		scrapeSuccess: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "scrape", "collector_success"),
+			"Whether an individual collector's scrape of a probed target succeeded.",
+			[]string{"target", "collector"}, nil,
+		),
+		calls: map[string]*probeCall{},
+	}
+}
+
+func (h *MultiTargetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	metrics := h.scrape(target)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newStaticCollector(metrics))
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// scrape runs every registered collector against target concurrently,
+// coalescing overlapping requests for the same target into one call.
+func (h *MultiTargetHandler) scrape(target string) []prometheus.Metric {
+	h.mu.Lock()
+	if call, ok := h.calls[target]; ok {
+		h.mu.Unlock()
+		call.wg.Wait()
+		return call.metrics
+	}
+
+	call := &probeCall{}
+	call.wg.Add(1)
+	h.calls[target] = call
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.calls, target)
+		h.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	runner := h.newRunner(target)
+	defer func() {
+		if err := runner.Close(); err != nil {
+			level.Warn(h.logger).Log("msg", "failed to close probe runner", "target", target, "err", err)
+		}
+	}()
+
+	var (
+		mu      sync.Mutex
+		metrics []prometheus.Metric
+		wg      sync.WaitGroup
+	)
+
+	for _, newCollector := range h.collectors {
+		c := newCollector(runner)
+
+		wg.Add(1)
+		go func(c Collector) {
+			defer wg.Done()
+
+			level.Debug(h.logger).Log("msg", "probing target", "target", target, "collector", c.Name())
+
+			start := time.Now()
+			collected := collectMetrics(c)
+			duration := time.Since(start).Seconds()
+			success := h.collectorSucceeded(collected)
+
+			mu.Lock()
+			metrics = append(metrics, collected...)
+			metrics = append(metrics,
+				prometheus.MustNewConstMetric(h.scrapeDuration, prometheus.GaugeValue, duration, target, c.Name()),
+				prometheus.MustNewConstMetric(h.scrapeSuccess, prometheus.GaugeValue, success, target, c.Name()),
+			)
+			mu.Unlock()
+		}(c)
+	}
+
+	wg.Wait()
+
+	call.metrics = metrics
+	return metrics
+}
+
+// collectorSucceeded inspects a single collector's own collected metrics
+// for the collector_error sample it emits on every Collect (0 on success,
+// 1 on failure) and returns the matching scrape_collector_success value.
+// Absent that sample entirely (e.g. a collector that doesn't use the
+// shared collectorError desc), it defaults to success.
+func (h *MultiTargetHandler) collectorSucceeded(collected []prometheus.Metric) float64 {
+	for _, m := range collected {
+		if m.Desc() != h.collectorError {
+			continue
+		}
+
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			continue
+		}
+
+		if pb.GetGauge().GetValue() != 0 {
+			return 0
+		}
+	}
+
+	return 1
+}
+
+// collectMetrics drains a Collector's Collect call into a slice, so it can
+// be merged with other collectors' output and the scrape gauges above.
+func collectMetrics(c Collector) []prometheus.Metric {
+	ch := make(chan prometheus.Metric)
+
+	var metrics []prometheus.Metric
+	done := make(chan struct{})
+	go func() {
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		close(done)
+	}()
+
+	c.Collect(ch)
+	close(ch)
+	<-done
+
+	return metrics
+}
+
+// staticCollector replays a fixed set of already-collected metrics, so
+// MultiTargetHandler can hand promhttp.HandlerFor a normal Gatherer without
+// re-running every sub-collector on every render.
+type staticCollector struct {
+	metrics []prometheus.Metric
+}
+
+func newStaticCollector(metrics []prometheus.Metric) *staticCollector {
+	return &staticCollector{metrics: metrics}
+}
+
+func (s *staticCollector) Describe(ch chan<- *prometheus.Desc) {
+	// Intentionally left blank: these metrics vary per target/probe and
+	// aren't known ahead of a scrape, same as prometheus.DescribeByCollect
+	// callers. promhttp.HandlerFor tolerates unchecked collectors.
+}
+
+func (s *staticCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range s.metrics {
+		ch <- m
+	}
+}
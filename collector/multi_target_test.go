@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robinmarechal/asterisk_exporter/cmd"
+)
+
+// fakeRunner is a no-op cmd.Runner that counts how many times it's closed.
+type fakeRunner struct {
+	closed int32
+}
+
+func (f *fakeRunner) PeersInfo() *cmd.PeersInfo             { return &cmd.PeersInfo{} }
+func (f *fakeRunner) SipChannelsInfo() *cmd.SipChannelsInfo { return &cmd.SipChannelsInfo{} }
+func (f *fakeRunner) UsersInfo() *cmd.UsersInfo             { return &cmd.UsersInfo{} }
+func (f *fakeRunner) RegistriesInfo() *cmd.RegistriesInfo   { return &cmd.RegistriesInfo{} }
+func (f *fakeRunner) Close() error {
+	atomic.AddInt32(&f.closed, 1)
+	return nil
+}
+
+// blockingCollector counts its Collect calls and blocks until release is
+// closed, so a test can force several concurrent /probe requests to land
+// inside the same in-flight scrape.
+type blockingCollector struct {
+	name    string
+	release chan struct{}
+	calls   int32
+}
+
+func (c *blockingCollector) Name() string                        { return c.name }
+func (c *blockingCollector) Describe(ch chan<- *prometheus.Desc) {}
+func (c *blockingCollector) Collect(ch chan<- prometheus.Metric) {
+	atomic.AddInt32(&c.calls, 1)
+	<-c.release
+}
+
+func TestMultiTargetHandler_CoalescesConcurrentScrapes(t *testing.T) {
+	var runnersBuilt int32
+	runner := &fakeRunner{}
+	collector := &blockingCollector{name: "sip", release: make(chan struct{})}
+
+	h := NewMultiTargetHandler("asterisk", log.NewNopLogger(),
+		func(target string) cmd.Runner {
+			atomic.AddInt32(&runnersBuilt, 1)
+			return runner
+		},
+		prometheus.NewDesc("asterisk_collector_error", "", []string{"collector"}, nil),
+		func(r cmd.Runner) Collector { return collector },
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.scrape("target1")
+		}()
+	}
+
+	// Give every goroutine a chance to call scrape and land in the same
+	// in-flight probeCall before letting the collector finish.
+	time.Sleep(20 * time.Millisecond)
+	close(collector.release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&runnersBuilt); got != 1 {
+		t.Errorf("expected newRunner to be called once for coalesced scrapes of the same target, got %d", got)
+	}
+	if got := atomic.LoadInt32(&collector.calls); got != 1 {
+		t.Errorf("expected the collector to be invoked once for coalesced scrapes of the same target, got %d", got)
+	}
+	if got := atomic.LoadInt32(&runner.closed); got != 1 {
+		t.Errorf("expected the runner to be closed exactly once, got %d", got)
+	}
+}
+
+func TestMultiTargetHandler_ClosesRunnerAfterScrape(t *testing.T) {
+	runner := &fakeRunner{}
+	collector := &blockingCollector{name: "sip", release: make(chan struct{})}
+	close(collector.release)
+
+	h := NewMultiTargetHandler("asterisk", log.NewNopLogger(),
+		func(target string) cmd.Runner { return runner },
+		prometheus.NewDesc("asterisk_collector_error", "", []string{"collector"}, nil),
+		func(r cmd.Runner) Collector { return collector },
+	)
+
+	h.scrape("target1")
+
+	if got := atomic.LoadInt32(&runner.closed); got != 1 {
+		t.Errorf("expected the runner to be closed once after scrape completes, got %d", got)
+	}
+}
+
+func TestMultiTargetHandler_CollectorSucceeded(t *testing.T) {
+	collectorError := prometheus.NewDesc("asterisk_collector_error", "", []string{"collector"}, nil)
+	h := &MultiTargetHandler{collectorError: collectorError}
+
+	success := prometheus.MustNewConstMetric(collectorError, prometheus.GaugeValue, 0, "sip")
+	failure := prometheus.MustNewConstMetric(collectorError, prometheus.GaugeValue, 1, "sip")
+
+	if got := h.collectorSucceeded([]prometheus.Metric{success}); got != 1 {
+		t.Errorf("expected success=1 when collector_error is 0, got %v", got)
+	}
+	if got := h.collectorSucceeded([]prometheus.Metric{failure}); got != 0 {
+		t.Errorf("expected success=0 when collector_error is 1, got %v", got)
+	}
+	if got := h.collectorSucceeded(nil); got != 1 {
+		t.Errorf("expected success=1 when no collector_error sample is present, got %v", got)
+	}
+}
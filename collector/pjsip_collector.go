@@ -0,0 +1,162 @@
+package collector
+
+import (
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robinmarechal/asterisk_exporter/cmd"
+)
+
+// pjsipCollector collector for all 'pjsip show ...' commands
+type pjsipCollector struct {
+	cmdRunner cmd.PJSIPRunner
+	logger    log.Logger
+
+	// pjsip show endpoints
+	endpointStatus *prometheus.Desc
+	// pjsip show contacts
+	contactStatus *prometheus.Desc
+	contactRTT    *prometheus.Desc
+	// pjsip show channelstats
+	activeChannels *prometheus.Desc
+	// pjsip show registrations
+	registrations *prometheus.Desc
+
+	collectorError *prometheus.Desc
+}
+
+type pjsipMetrics struct {
+	EndpointsInfo     *cmd.PJSIPEndpointsInfo
+	ContactsInfo      *cmd.PJSIPContactsInfo
+	RegistrationsInfo *cmd.PJSIPRegistrationsInfo
+	ChannelStatsInfo  *cmd.PJSIPChannelStatsInfo
+}
+
+func NewPjsipCollector(prefix string, cmdRunner cmd.PJSIPRunner, logger log.Logger, collectorError *prometheus.Desc) Collector {
+	return &pjsipCollector{
+		cmdRunner:      cmdRunner,
+		logger:         logger,
+		collectorError: collectorError,
+		endpointStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(prefix, "pjsip", "endpoint_status"),
+			"Status of individual PJSIP endpoints",
+			[]string{"endpoint", "aor", "status"}, nil,
+		),
+		contactStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(prefix, "pjsip", "contact_status"),
+			"Status of individual PJSIP contacts",
+			[]string{"contact", "status"}, nil,
+		),
+		contactRTT: prometheus.NewDesc(
+			prometheus.BuildFQName(prefix, "pjsip", "contact_rtt_milliseconds"),
+			"Round-trip time of the last qualify of an individual PJSIP contact",
+			[]string{"contact"}, nil,
+		),
+		activeChannels: prometheus.NewDesc(
+			prometheus.BuildFQName(prefix, "pjsip", "active_channels"),
+			"Number of active PJSIP channels",
+			nil, nil,
+		),
+		registrations: prometheus.NewDesc(
+			prometheus.BuildFQName(prefix, "pjsip", "registrations"),
+			"Number of outbound PJSIP registrations, by state",
+			[]string{"state"}, nil,
+		),
+	}
+}
+
+func (c *pjsipCollector) Name() string {
+	return "pjsip"
+}
+
+func (c *pjsipCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.endpointStatus
+	ch <- c.contactStatus
+	ch <- c.contactRTT
+	ch <- c.activeChannels
+	ch <- c.registrations
+}
+
+func (c *pjsipCollector) Collect(ch chan<- prometheus.Metric) {
+	level.Debug(c.logger).Log("msg", "collecting pjsip metrics")
+	metrics, err := collectPjsipMetrics(c.cmdRunner)
+
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(c.collectorError, prometheus.GaugeValue, 1, c.Name())
+		level.Error(c.logger).Log("err", err)
+		return
+	}
+
+	level.Debug(c.logger).Log("msg", "pjsip metrics collected")
+
+	ch <- prometheus.MustNewConstMetric(c.collectorError, prometheus.GaugeValue, 0, c.Name())
+
+	c.updateMetrics(metrics, ch)
+}
+
+func collectPjsipMetrics(c cmd.PJSIPRunner) (*pjsipMetrics, error) {
+	endpoints, err := c.PJSIPEndpointsInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	contacts, err := c.PJSIPContactsInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	registrations, err := c.PJSIPRegistrationsInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	channelStats, err := c.PJSIPChannelStatsInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pjsipMetrics{
+		EndpointsInfo:     endpoints,
+		ContactsInfo:      contacts,
+		RegistrationsInfo: registrations,
+		ChannelStatsInfo:  channelStats,
+	}, nil
+}
+
+func (c *pjsipCollector) updateMetrics(values *pjsipMetrics, ch chan<- prometheus.Metric) {
+	for _, endpoint := range values.EndpointsInfo.Endpoints {
+		ch <- prometheus.MustNewConstMetric(
+			c.endpointStatus,
+			prometheus.GaugeValue,
+			1,
+			endpoint.Name, endpoint.AOR, endpoint.Status,
+		)
+	}
+
+	for _, contact := range values.ContactsInfo.Contacts {
+		ch <- prometheus.MustNewConstMetric(
+			c.contactStatus,
+			prometheus.GaugeValue,
+			1,
+			contact.URI, contact.Status,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.contactRTT,
+			prometheus.GaugeValue,
+			contact.RttMs,
+			contact.URI,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.activeChannels, prometheus.GaugeValue, float64(values.ChannelStatsInfo.ActiveChannels))
+
+	registrationsByState := map[string]int{}
+	for _, registration := range values.RegistrationsInfo.Registrations {
+		registrationsByState[registration.State]++
+	}
+	for state, count := range registrationsByState {
+		ch <- prometheus.MustNewConstMetric(c.registrations, prometheus.GaugeValue, float64(count), state)
+	}
+
+	level.Debug(c.logger).Log("msg", "pjsip metrics built")
+}
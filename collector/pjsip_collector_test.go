@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/robinmarechal/asterisk_exporter/cmd"
+)
+
+// fakePJSIPRunner is a cmd.PJSIPRunner backed by fixed responses, so
+// pjsipCollector can be exercised without shelling out or dialing AMI.
+type fakePJSIPRunner struct {
+	endpoints     *cmd.PJSIPEndpointsInfo
+	contacts      *cmd.PJSIPContactsInfo
+	registrations *cmd.PJSIPRegistrationsInfo
+	channelStats  *cmd.PJSIPChannelStatsInfo
+	err           error
+}
+
+func (f *fakePJSIPRunner) PJSIPEndpointsInfo() (*cmd.PJSIPEndpointsInfo, error) {
+	return f.endpoints, f.err
+}
+func (f *fakePJSIPRunner) PJSIPContactsInfo() (*cmd.PJSIPContactsInfo, error) {
+	return f.contacts, f.err
+}
+func (f *fakePJSIPRunner) PJSIPRegistrationsInfo() (*cmd.PJSIPRegistrationsInfo, error) {
+	return f.registrations, f.err
+}
+func (f *fakePJSIPRunner) PJSIPChannelStatsInfo() (*cmd.PJSIPChannelStatsInfo, error) {
+	return f.channelStats, f.err
+}
+
+func collectAll(c Collector) []prometheus.Metric {
+	ch := make(chan prometheus.Metric, 64)
+	c.Collect(ch)
+	close(ch)
+
+	var metrics []prometheus.Metric
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+func findMetric(t *testing.T, metrics []prometheus.Metric, desc *prometheus.Desc) *dto.Metric {
+	t.Helper()
+
+	for _, m := range metrics {
+		if m.Desc() != desc {
+			continue
+		}
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatalf("writing metric: %v", err)
+		}
+		return &pb
+	}
+	return nil
+}
+
+func TestPjsipCollector_Collect(t *testing.T) {
+	runner := &fakePJSIPRunner{
+		endpoints: &cmd.PJSIPEndpointsInfo{Endpoints: []cmd.PJSIPEndpoint{
+			{Name: "1000", AOR: "1000", Status: "Not in use"},
+		}},
+		contacts: &cmd.PJSIPContactsInfo{Contacts: []cmd.PJSIPContact{
+			{URI: "sip:1000@192.168.1.50:5060", Status: "Avail", RttMs: 23.456},
+		}},
+		registrations: &cmd.PJSIPRegistrationsInfo{Registrations: []cmd.PJSIPRegistration{
+			{Name: "reg1", State: "Registered"},
+			{Name: "reg2", State: "Registered"},
+		}},
+		channelStats: &cmd.PJSIPChannelStatsInfo{ActiveChannels: 2},
+	}
+
+	collectorError := prometheus.NewDesc("asterisk_collector_error", "", []string{"collector"}, nil)
+	c := NewPjsipCollector("asterisk", runner, log.NewNopLogger(), collectorError)
+
+	metrics := collectAll(c)
+
+	if pb := findMetric(t, metrics, collectorError); pb == nil || pb.GetGauge().GetValue() != 0 {
+		t.Errorf("expected collector_error=0 on success, got %v", pb)
+	}
+
+	if pb := findMetric(t, metrics, c.(*pjsipCollector).activeChannels); pb == nil || pb.GetGauge().GetValue() != 2 {
+		t.Errorf("expected active_channels=2, got %v", pb)
+	}
+
+	var registeredCount int
+	for _, m := range metrics {
+		if m.Desc() != c.(*pjsipCollector).registrations {
+			continue
+		}
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatalf("writing metric: %v", err)
+		}
+		registeredCount += int(pb.GetGauge().GetValue())
+	}
+	if registeredCount != 2 {
+		t.Errorf("expected 2 registrations in state Registered, got %d", registeredCount)
+	}
+}
+
+func TestPjsipCollector_Collect_ReportsErrorOnFailure(t *testing.T) {
+	runner := &fakePJSIPRunner{err: errors.New("boom")}
+
+	collectorError := prometheus.NewDesc("asterisk_collector_error", "", []string{"collector"}, nil)
+	c := NewPjsipCollector("asterisk", runner, log.NewNopLogger(), collectorError)
+
+	metrics := collectAll(c)
+
+	if len(metrics) != 1 {
+		t.Fatalf("expected only the collector_error sample on failure, got %d metrics", len(metrics))
+	}
+
+	pb := findMetric(t, metrics, collectorError)
+	if pb == nil || pb.GetGauge().GetValue() != 1 {
+		t.Errorf("expected collector_error=1 on failure, got %v", pb)
+	}
+}
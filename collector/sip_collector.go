@@ -1,6 +1,10 @@
 package collector
 
 import (
+	"regexp"
+	"sync"
+	"time"
+
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
@@ -9,9 +13,16 @@ import (
 
 // sipCollector collector for all 'sip show ...' commands
 type sipCollector struct {
-	cmdRunner *cmd.CmdRunner
+	cmdRunner cmd.Runner
 	logger    log.Logger
 
+	// peer_status and registration_status cardinality control, mirroring
+	// node_exporter's collector.systemd.unit-whitelist/unit-blacklist.
+	peerInclude         *regexp.Regexp
+	peerExclude         *regexp.Regexp
+	registrationInclude *regexp.Regexp
+	registrationExclude *regexp.Regexp
+
 	// sip show peers
 	totalPeers              *prometheus.Desc
 	totalMonitoredOnline    *prometheus.Desc
@@ -39,6 +50,16 @@ type sipCollector struct {
 	offlineRegistrationsCount *prometheus.Desc
 	registrationStatus        *prometheus.Desc
 
+	// sip show peer <name>, opt-in and rate-limited
+	detailRunner      cmd.DetailedPeerRunner
+	detailMinInterval time.Duration
+	detailCacheMu     sync.Mutex
+	detailCache       map[string]*cmd.PeerDetail
+	detailCacheAt     map[string]time.Time
+	peerQualifyRTT    *prometheus.Desc
+	peerLastQualify   *prometheus.Desc
+	peerInfo          *prometheus.Desc
+
 	collectorError *prometheus.Desc
 }
 
@@ -49,11 +70,37 @@ type sipMetrics struct {
 	RegistriesInfo  *cmd.RegistriesInfo
 }
 
-func NewSipCollector(prefix string, cmdRunner *cmd.CmdRunner, logger log.Logger, collectorError *prometheus.Desc) Collector {
+// SipFilters holds the compiled peer/registration include/exclude patterns
+// used by sipCollector to keep per-peer and per-registration metric
+// cardinality in check on PBXes with large peer counts.
+type SipFilters struct {
+	PeerInclude         *regexp.Regexp
+	PeerExclude         *regexp.Regexp
+	RegistrationInclude *regexp.Regexp
+	RegistrationExclude *regexp.Regexp
+}
+
+// DetailedPeerOptions enables the opt-in `sip show peer <name>` lookups
+// used for per-peer qualify RTT/info metrics. Runner is nil when the
+// feature is disabled.
+type DetailedPeerOptions struct {
+	Runner      cmd.DetailedPeerRunner
+	MinInterval time.Duration
+}
+
+func NewSipCollector(prefix string, cmdRunner cmd.Runner, logger log.Logger, collectorError *prometheus.Desc, filters SipFilters, detailedPeers DetailedPeerOptions) Collector {
 	return &sipCollector{
-		cmdRunner:      cmdRunner,
-		logger:         logger,
-		collectorError: collectorError,
+		cmdRunner:           cmdRunner,
+		logger:              logger,
+		collectorError:      collectorError,
+		peerInclude:         filters.PeerInclude,
+		peerExclude:         filters.PeerExclude,
+		registrationInclude: filters.RegistrationInclude,
+		registrationExclude: filters.RegistrationExclude,
+		detailRunner:        detailedPeers.Runner,
+		detailMinInterval:   detailedPeers.MinInterval,
+		detailCache:         map[string]*cmd.PeerDetail{},
+		detailCacheAt:       map[string]time.Time{},
 		totalPeers: prometheus.NewDesc(
 			prometheus.BuildFQName(prefix, "sip", "current_peers"),
 			"Number of SIP peers",
@@ -134,7 +181,109 @@ func NewSipCollector(prefix string, cmdRunner *cmd.CmdRunner, logger log.Logger,
 			"Status of individual SIP registrations",
 			[]string{"username", "state"}, nil,
 		),
+		peerQualifyRTT: prometheus.NewDesc(
+			prometheus.BuildFQName(prefix, "sip", "peer_qualify_rtt_milliseconds"),
+			"Round-trip time of the last successful qualify of a SIP peer",
+			[]string{"peer"}, nil,
+		),
+		peerLastQualify: prometheus.NewDesc(
+			prometheus.BuildFQName(prefix, "sip", "peer_last_qualify_seconds"),
+			"Unix timestamp of the last successful qualify of a SIP peer; absent while the peer has never been successfully qualified",
+			[]string{"peer"}, nil,
+		),
+		peerInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(prefix, "sip", "peer_info"),
+			"Labeled info metric (always 1) with a SIP peer's user agent and registered contact",
+			[]string{"peer", "useragent", "contact"}, nil,
+		),
+	}
+}
+
+// matchesFilter reports whether name should be kept: it must match include
+// (nil include is treated as matching everything) and must not match
+// exclude (nil exclude is treated as matching nothing).
+func matchesFilter(name string, include, exclude *regexp.Regexp) bool {
+	if include != nil && !include.MatchString(name) {
+		return false
+	}
+	if exclude != nil && exclude.MatchString(name) {
+		return false
+	}
+	return true
+}
+
+// detailFetchConcurrency bounds how many `sip show peer <name>` lookups run
+// at once. Peers share one detailMinInterval, so on a cold cache they all go
+// stale together; fetching them one at a time in collectPeerDetails would
+// turn a single scrape into a fully serial chain of thousands of CLI
+// round-trips.
+const detailFetchConcurrency = 16
+
+// collectPeerDetails fans collectPeerDetail out across peers through a
+// bounded worker pool, instead of fetching each one serially in the
+// updateMetrics loop.
+func (c *sipCollector) collectPeerDetails(peers []string, ch chan<- prometheus.Metric) {
+	sem := make(chan struct{}, detailFetchConcurrency)
+	var wg sync.WaitGroup
+
+	for _, peer := range peers {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(peer string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			c.collectPeerDetail(peer, ch)
+		}(peer)
+	}
+
+	wg.Wait()
+}
+
+// collectPeerDetail emits the opt-in per-peer qualify/info metrics for
+// peer, issuing a fresh `sip show peer <name>` at most once per
+// detailMinInterval and reusing the cached result otherwise, so enabling
+// this on a PBX with thousands of peers doesn't turn every scrape into
+// thousands of CLI round-trips.
+func (c *sipCollector) collectPeerDetail(peer string, ch chan<- prometheus.Metric) {
+	c.detailCacheMu.Lock()
+	detail, ok := c.detailCache[peer]
+	fetchedAt, fetchedBefore := c.detailCacheAt[peer]
+	stale := !fetchedBefore || time.Since(fetchedAt) >= c.detailMinInterval
+	c.detailCacheMu.Unlock()
+
+	if !ok || stale {
+		fresh, err := c.detailRunner.PeerDetail(peer)
+		if err != nil {
+			level.Error(c.logger).Log("msg", "failed to fetch peer detail", "peer", peer, "err", err)
+		} else {
+			// A fetch that didn't parse a successful qualify (peer transiently
+			// unreachable, no Go error) still refreshes Contact/Useragent, but
+			// must not clobber the last known-good RTT/timestamp with a zero
+			// value: that would misreport "unreachable" as a perfect ping and
+			// make peerLastQualify flap from present back to absent.
+			if fresh.LastQualifiedAt.IsZero() && detail != nil {
+				fresh.QualifyRTTMillis = detail.QualifyRTTMillis
+				fresh.LastQualifiedAt = detail.LastQualifiedAt
+			}
+
+			detail = fresh
+			c.detailCacheMu.Lock()
+			c.detailCache[peer] = detail
+			c.detailCacheAt[peer] = time.Now()
+			c.detailCacheMu.Unlock()
+		}
+	}
+
+	if detail == nil {
+		return
 	}
+
+	ch <- prometheus.MustNewConstMetric(c.peerQualifyRTT, prometheus.GaugeValue, detail.QualifyRTTMillis, peer)
+	if !detail.LastQualifiedAt.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.peerLastQualify, prometheus.GaugeValue, float64(detail.LastQualifiedAt.Unix()), peer)
+	}
+	ch <- prometheus.MustNewConstMetric(c.peerInfo, prometheus.GaugeValue, 1, peer, detail.Useragent, detail.Contact)
 }
 
 func (c *sipCollector) Name() string {
@@ -158,6 +307,9 @@ func (c *sipCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.onlineRegistrationsCount
 	ch <- c.offlineRegistrationsCount
 	ch <- c.registrationStatus
+	ch <- c.peerQualifyRTT
+	ch <- c.peerLastQualify
+	ch <- c.peerInfo
 }
 
 func (c *sipCollector) Collect(ch chan<- prometheus.Metric) {
@@ -177,7 +329,7 @@ func (c *sipCollector) Collect(ch chan<- prometheus.Metric) {
 	c.updateMetrics(metrics, ch)
 }
 
-func collectSipMetrics(c *cmd.CmdRunner) (*sipMetrics, error) {
+func collectSipMetrics(c cmd.Runner) (*sipMetrics, error) {
 	metrics := &sipMetrics{
 		PeersInfo:       c.PeersInfo(),
 		SipChannelsInfo: c.SipChannelsInfo(),
@@ -196,13 +348,26 @@ func (c *sipCollector) updateMetrics(values *sipMetrics, ch chan<- prometheus.Me
 	ch <- prometheus.MustNewConstMetric(c.totalSipStatusUnknown, prometheus.GaugeValue, float64(values.PeersInfo.PeersStatusUnknown))
 	ch <- prometheus.MustNewConstMetric(c.totalSipStatusQualified, prometheus.GaugeValue, float64(values.PeersInfo.PeersStatusQualified))
 
+	var detailPeers []string
 	for _, peer := range values.PeersInfo.IndividualPeers {
+		if !matchesFilter(peer.Name, c.peerInclude, c.peerExclude) {
+			continue
+		}
+
 		ch <- prometheus.MustNewConstMetric(
 			c.peerStatus,
 			prometheus.GaugeValue,
 			1, // 1 указывает на наличие пира, 0 на его отсутствие.
 			peer.Name, peer.Status,
 		)
+
+		if c.detailRunner != nil {
+			detailPeers = append(detailPeers, peer.Name)
+		}
+	}
+
+	if len(detailPeers) > 0 {
+		c.collectPeerDetails(detailPeers, ch)
 	}
 
 	ch <- prometheus.MustNewConstMetric(c.dialogsActive, prometheus.GaugeValue, float64(values.SipChannelsInfo.ActiveSipDialogs))
@@ -216,6 +381,10 @@ func (c *sipCollector) updateMetrics(values *sipMetrics, ch chan<- prometheus.Me
 	ch <- prometheus.MustNewConstMetric(c.offlineRegistrationsCount, prometheus.GaugeValue, float64(values.RegistriesInfo.OfflineRegistrations))
 
 	for _, registry := range values.RegistriesInfo.IndividualRegistrations {
+		if !matchesFilter(registry.Username, c.registrationInclude, c.registrationExclude) {
+			continue
+		}
+
 		ch <- prometheus.MustNewConstMetric(
 			c.registrationStatus,
 			prometheus.GaugeValue,
@@ -0,0 +1,204 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/robinmarechal/asterisk_exporter/cmd"
+)
+
+func TestMatchesFilter(t *testing.T) {
+	cases := []struct {
+		name             string
+		peer             string
+		include, exclude *regexp.Regexp
+		want             bool
+	}{
+		{
+			name: "nil include and exclude match everything",
+			peer: "SIP/1000",
+			want: true,
+		},
+		{
+			name:    "default include pattern matches everything",
+			peer:    "SIP/1000",
+			include: regexp.MustCompile(".+"),
+			want:    true,
+		},
+		{
+			name:    "empty-pattern include still compiles to match-all",
+			peer:    "SIP/1000",
+			include: regexp.MustCompile(""),
+			want:    true,
+		},
+		{
+			name:    "peer not matching include is dropped",
+			peer:    "SIP/1000",
+			include: regexp.MustCompile("^trunk-"),
+			want:    false,
+		},
+		{
+			name:    "exclude wins over a matching include",
+			peer:    "SIP/1000",
+			include: regexp.MustCompile(".+"),
+			exclude: regexp.MustCompile("^SIP/"),
+			want:    false,
+		},
+		{
+			name:    "peer matching include and not matching exclude is kept",
+			peer:    "SIP/1000",
+			include: regexp.MustCompile("^SIP/"),
+			exclude: regexp.MustCompile("^trunk-"),
+			want:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := matchesFilter(tc.peer, tc.include, tc.exclude); got != tc.want {
+				t.Errorf("matchesFilter(%q) = %v, want %v", tc.peer, got, tc.want)
+			}
+		})
+	}
+}
+
+// fakeDetailRunner tracks how many PeerDetail calls are in flight at once,
+// so tests can assert collectPeerDetails actually fans work out instead of
+// running it serially.
+type fakeDetailRunner struct {
+	mu            sync.Mutex
+	calls         int
+	current       int
+	maxConcurrent int
+}
+
+func (f *fakeDetailRunner) PeerDetail(name string) (*cmd.PeerDetail, error) {
+	f.mu.Lock()
+	f.calls++
+	f.current++
+	if f.current > f.maxConcurrent {
+		f.maxConcurrent = f.current
+	}
+	f.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	f.mu.Lock()
+	f.current--
+	f.mu.Unlock()
+
+	return &cmd.PeerDetail{}, nil
+}
+
+func TestSipCollector_CollectPeerDetailsIsBoundedAndConcurrent(t *testing.T) {
+	fake := &fakeDetailRunner{}
+	collectorError := prometheus.NewDesc("asterisk_collector_error", "", []string{"collector"}, nil)
+
+	sc := NewSipCollector("asterisk", nil, log.NewNopLogger(), collectorError, SipFilters{}, DetailedPeerOptions{
+		Runner:      fake,
+		MinInterval: time.Hour,
+	}).(*sipCollector)
+
+	peers := make([]string, 50)
+	for i := range peers {
+		peers[i] = fmt.Sprintf("peer%d", i)
+	}
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+
+	sc.collectPeerDetails(peers, ch)
+	close(ch)
+	<-done
+
+	if fake.calls != len(peers) {
+		t.Errorf("expected PeerDetail to be called once per peer (%d), got %d", len(peers), fake.calls)
+	}
+	if fake.maxConcurrent <= 1 {
+		t.Errorf("expected PeerDetail calls to overlap, max observed concurrency was %d", fake.maxConcurrent)
+	}
+	if fake.maxConcurrent > detailFetchConcurrency {
+		t.Errorf("expected concurrency to be bounded by %d, got %d", detailFetchConcurrency, fake.maxConcurrent)
+	}
+}
+
+// sequenceDetailRunner returns one *cmd.PeerDetail per call, in order,
+// regardless of which peer is asked for.
+type sequenceDetailRunner struct {
+	responses []*cmd.PeerDetail
+	call      int
+}
+
+func (s *sequenceDetailRunner) PeerDetail(name string) (*cmd.PeerDetail, error) {
+	resp := s.responses[s.call]
+	s.call++
+	return resp, nil
+}
+
+func TestSipCollector_CollectPeerDetailKeepsLastGoodQualifyOnTransientFailure(t *testing.T) {
+	goodAt := time.Now().Add(-time.Minute)
+	fake := &sequenceDetailRunner{responses: []*cmd.PeerDetail{
+		{QualifyRTTMillis: 23, LastQualifiedAt: goodAt, Useragent: "Zoiper"},
+		{QualifyRTTMillis: 0, LastQualifiedAt: time.Time{}, Useragent: "Zoiper"},
+	}}
+	collectorError := prometheus.NewDesc("asterisk_collector_error", "", []string{"collector"}, nil)
+
+	sc := NewSipCollector("asterisk", nil, log.NewNopLogger(), collectorError, SipFilters{}, DetailedPeerOptions{
+		Runner:      fake,
+		MinInterval: 0,
+	}).(*sipCollector)
+
+	drain := func() []prometheus.Metric {
+		ch := make(chan prometheus.Metric, 10)
+		sc.collectPeerDetail("peer0", ch)
+		close(ch)
+		var metrics []prometheus.Metric
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		return metrics
+	}
+
+	drain() // primes the cache with the successful qualify
+
+	metrics := drain() // refresh observes a transient failure (no error, no qualify line)
+
+	var sawRTT, sawLastQualify bool
+	for _, m := range metrics {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatalf("writing metric: %v", err)
+		}
+
+		switch m.Desc() {
+		case sc.peerQualifyRTT:
+			sawRTT = true
+			if pb.GetGauge().GetValue() != 23 {
+				t.Errorf("expected cached QualifyRTTMillis 23 to survive a transient failure, got %v", pb.GetGauge().GetValue())
+			}
+		case sc.peerLastQualify:
+			sawLastQualify = true
+			if pb.GetGauge().GetValue() != float64(goodAt.Unix()) {
+				t.Errorf("expected cached LastQualifiedAt to survive a transient failure, got %v", pb.GetGauge().GetValue())
+			}
+		}
+	}
+
+	if !sawRTT {
+		t.Error("expected a peerQualifyRTT metric")
+	}
+	if !sawLastQualify {
+		t.Error("expected a peerLastQualify metric to still be present after a transient failure")
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/version"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/robinmarechal/asterisk_exporter/cmd"
+	"github.com/robinmarechal/asterisk_exporter/collector"
+)
+
+const namespace = "asterisk"
+
+var (
+	listenAddress = kingpin.Flag("web.listen-address", "Address to listen on for web interface and telemetry.").Default(":9153").String()
+	metricsPath   = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").String()
+
+	asteriskBinPath = kingpin.Flag("asterisk.binary", "Path to the asterisk CLI binary.").Default("asterisk").String()
+	transport       = kingpin.Flag("asterisk.transport", "Backend used to talk to Asterisk: cli or ami.").Default("cli").Enum("cli", "ami")
+
+	amiAddress  = kingpin.Flag("asterisk.ami.address", "host:port of the Asterisk Manager Interface (used when --asterisk.transport=ami).").Default("127.0.0.1:5038").String()
+	amiUsername = kingpin.Flag("asterisk.ami.username", "AMI username (used when --asterisk.transport=ami).").String()
+	amiSecret   = kingpin.Flag("asterisk.ami.secret", "AMI secret (used when --asterisk.transport=ami).").String()
+
+	enablePjsip = kingpin.Flag("collector.pjsip", "Enable the PJSIP collector, for Asterisk versions where chan_sip is deprecated or removed.").Bool()
+
+	peerIncludePattern         = kingpin.Flag("collector.sip.peer-include", "Regexp of peer names to include in peer_status metrics. Names that don't match are excluded.").Default(".+").String()
+	peerExcludePattern         = kingpin.Flag("collector.sip.peer-exclude", "Regexp of peer names to exclude from peer_status metrics. This takes precedence over peer-include.").Default("").String()
+	registrationIncludePattern = kingpin.Flag("collector.sip.registration-include", "Regexp of registration usernames to include in registration_status metrics. Names that don't match are excluded.").Default(".+").String()
+	registrationExcludePattern = kingpin.Flag("collector.sip.registration-exclude", "Regexp of registration usernames to exclude from registration_status metrics. This takes precedence over registration-include.").Default("").String()
+
+	detailedPeers         = kingpin.Flag("collector.sip.detailed-peers", "Additionally issue 'sip show peer <name>' per peer to expose qualify RTT and user agent/contact info. One extra CLI round-trip per peer per refresh interval.").Bool()
+	detailedPeersInterval = kingpin.Flag("collector.sip.detailed-peers-interval", "Minimum time between 'sip show peer <name>' refreshes for a given peer.").Default("1m").Duration()
+)
+
+// compileFilter compiles pattern into a regexp, or returns nil for an empty
+// pattern so callers can treat "no pattern configured" distinctly from "an
+// empty-string pattern", which would otherwise match every name. An invalid
+// pattern is reported as a usage error and exits the process, the same as
+// an invalid --asterisk.transport value, instead of panicking with a raw
+// regexp stack trace.
+func compileFilter(flagName, pattern string) *regexp.Regexp {
+	re, err := parseFilterPattern(pattern)
+	if err != nil {
+		kingpin.Fatalf("invalid --%s %q: %v", flagName, pattern, err)
+	}
+	return re
+}
+
+// parseFilterPattern is split out from compileFilter so the non-exiting
+// compile logic can be tested directly.
+func parseFilterPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+func newRunner(logger log.Logger) cmd.Runner {
+	switch *transport {
+	case "ami":
+		level.Info(logger).Log("msg", "using AMI transport", "address", *amiAddress)
+		return cmd.NewAMIRunner(*amiAddress, *amiUsername, *amiSecret)
+	default:
+		level.Info(logger).Log("msg", "using CLI transport", "binary", *asteriskBinPath)
+		return cmd.NewCmdRunner(*asteriskBinPath)
+	}
+}
+
+// probeRunnerFactory builds the runner used for /probe requests. Probed
+// targets are always reached over AMI (host:port from the "target" query
+// parameter), since that's the transport built for scraping a PBX that
+// isn't the local machine; the shared --asterisk.ami.username/secret
+// credentials are reused for every target.
+func probeRunnerFactory() collector.RunnerFactory {
+	return func(target string) cmd.Runner {
+		return cmd.NewAMIRunner(target, *amiUsername, *amiSecret)
+	}
+}
+
+func probeSipCollectorFactory(filters collector.SipFilters, collectorError *prometheus.Desc, logger log.Logger) collector.CollectorFactory {
+	return func(runner cmd.Runner) collector.Collector {
+		return collector.NewSipCollector(namespace, runner, logger, collectorError, filters, collector.DetailedPeerOptions{})
+	}
+}
+
+func probePjsipCollectorFactory(collectorError *prometheus.Desc, logger log.Logger) collector.CollectorFactory {
+	return func(runner cmd.Runner) collector.Collector {
+		pjsipRunner, ok := runner.(cmd.PJSIPRunner)
+		if !ok {
+			level.Warn(logger).Log("msg", "probed runner doesn't support PJSIP")
+			return collector.NewErrorCollector("pjsip", collectorError)
+		}
+		return collector.NewPjsipCollector(namespace, pjsipRunner, logger, collectorError)
+	}
+}
+
+func main() {
+	kingpin.Version(version.Print("asterisk_exporter"))
+	kingpin.HelpFlag.Short('h')
+	kingpin.Parse()
+
+	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
+
+	level.Info(logger).Log("msg", "starting asterisk_exporter", "version", version.Info())
+
+	runner := newRunner(logger)
+
+	collectorError := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "collector_error"),
+		"Indicates whether a collector's last scrape failed (1 for failure, 0 for success).",
+		[]string{"collector"}, nil,
+	)
+
+	sipFilters := collector.SipFilters{
+		PeerInclude:         compileFilter("collector.sip.peer-include", *peerIncludePattern),
+		PeerExclude:         compileFilter("collector.sip.peer-exclude", *peerExcludePattern),
+		RegistrationInclude: compileFilter("collector.sip.registration-include", *registrationIncludePattern),
+		RegistrationExclude: compileFilter("collector.sip.registration-exclude", *registrationExcludePattern),
+	}
+
+	detailedPeerOptions := collector.DetailedPeerOptions{MinInterval: *detailedPeersInterval}
+	if *detailedPeers {
+		if detailRunner, ok := runner.(cmd.DetailedPeerRunner); ok {
+			detailedPeerOptions.Runner = detailRunner
+		} else {
+			level.Warn(logger).Log("msg", "--collector.sip.detailed-peers is set but the configured transport doesn't support it")
+		}
+	}
+
+	sipCollector := collector.NewSipCollector(namespace, runner, logger, collectorError, sipFilters, detailedPeerOptions)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(sipCollector)
+
+	if *enablePjsip {
+		if pjsipRunner, ok := runner.(cmd.PJSIPRunner); ok {
+			registry.MustRegister(collector.NewPjsipCollector(namespace, pjsipRunner, logger, collectorError))
+		} else {
+			level.Warn(logger).Log("msg", "--collector.pjsip is set but the configured transport doesn't support it")
+		}
+	}
+
+	probeCollectors := []collector.CollectorFactory{
+		probeSipCollectorFactory(sipFilters, collectorError, logger),
+	}
+	if *enablePjsip {
+		probeCollectors = append(probeCollectors, probePjsipCollectorFactory(collectorError, logger))
+	}
+
+	http.Handle("/probe", collector.NewMultiTargetHandler(namespace, logger, probeRunnerFactory(), collectorError, probeCollectors...))
+
+	http.Handle(*metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html>
+<head><title>Asterisk Exporter</title></head>
+<body>
+<h1>Asterisk Exporter</h1>
+<p><a href="%s">Metrics</a></p>
+</body>
+</html>`, *metricsPath)
+	})
+
+	level.Info(logger).Log("msg", "listening", "address", *listenAddress)
+	if err := http.ListenAndServe(*listenAddress, nil); err != nil {
+		level.Error(logger).Log("msg", "http server error", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/robinmarechal/asterisk_exporter/cmd"
+)
+
+// nonPJSIPRunner is a cmd.Runner that deliberately doesn't implement
+// cmd.PJSIPRunner, so probePjsipCollectorFactory's fallback path can be
+// exercised without a real AMI/CLI runner.
+type nonPJSIPRunner struct{}
+
+func (nonPJSIPRunner) PeersInfo() *cmd.PeersInfo             { return &cmd.PeersInfo{} }
+func (nonPJSIPRunner) SipChannelsInfo() *cmd.SipChannelsInfo { return &cmd.SipChannelsInfo{} }
+func (nonPJSIPRunner) UsersInfo() *cmd.UsersInfo             { return &cmd.UsersInfo{} }
+func (nonPJSIPRunner) RegistriesInfo() *cmd.RegistriesInfo   { return &cmd.RegistriesInfo{} }
+func (nonPJSIPRunner) Close() error                          { return nil }
+
+// TestProbePjsipCollectorFactory_FallsBackOnNonPJSIPRunner is a regression
+// test for a type assertion that has panicked here twice before (a91cee4,
+// eee7ef3): a runner that doesn't implement cmd.PJSIPRunner must yield a
+// collector_error=1 sample, not a panic.
+func TestProbePjsipCollectorFactory_FallsBackOnNonPJSIPRunner(t *testing.T) {
+	collectorError := prometheus.NewDesc("asterisk_collector_error", "", []string{"collector"}, nil)
+	factory := probePjsipCollectorFactory(collectorError, log.NewNopLogger())
+
+	c := factory(nonPJSIPRunner{})
+
+	ch := make(chan prometheus.Metric, 1)
+	c.Collect(ch)
+	close(ch)
+
+	var metrics []prometheus.Metric
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+
+	if len(metrics) != 1 {
+		t.Fatalf("expected exactly one metric, got %d", len(metrics))
+	}
+
+	var pb dto.Metric
+	if err := metrics[0].Write(&pb); err != nil {
+		t.Fatalf("writing metric: %v", err)
+	}
+	if pb.GetGauge().GetValue() != 1 {
+		t.Errorf("expected collector_error=1, got %v", pb.GetGauge().GetValue())
+	}
+}
+
+func TestParseFilterPattern(t *testing.T) {
+	re, err := parseFilterPattern("")
+	if err != nil || re != nil {
+		t.Errorf("expected an empty pattern to compile to a nil regexp with no error, got %v, %v", re, err)
+	}
+
+	re, err = parseFilterPattern("^SIP/.+")
+	if err != nil {
+		t.Fatalf("unexpected error compiling a valid pattern: %v", err)
+	}
+	if !re.MatchString("SIP/1000") {
+		t.Errorf("expected compiled pattern to match, got %v", re)
+	}
+
+	if _, err := parseFilterPattern("("); err == nil {
+		t.Error("expected an unbalanced paren to fail to compile instead of panicking")
+	}
+}